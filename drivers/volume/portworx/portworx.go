@@ -0,0 +1,61 @@
+// Package portworx implements the drivers/volume.StorkVolumeDriver
+// interface on top of the Portworx SDK/API client.
+package portworx
+
+import (
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/api/client"
+	"github.com/libopenstorage/stork/drivers/volume"
+)
+
+// Driver implements volume.StorkVolumeDriver against a live Portworx
+// cluster.
+type Driver struct {
+	volDriver client.VolumeDriver
+}
+
+// InspectVolume returns Portworx's view of volumeID.
+func (d *Driver) InspectVolume(volumeID string) (*volume.Info, error) {
+	vols, err := d.volDriver.Inspect([]string{volumeID})
+	if err != nil {
+		return nil, err
+	}
+	if len(vols) == 0 {
+		return nil, nil
+	}
+
+	v := vols[0]
+	info := &volume.Info{
+		VolumeID:   v.Id,
+		VolumeName: v.Locator.Name,
+	}
+	if v.Source != nil {
+		info.ParentID = v.Source.Parent
+	}
+	return info, nil
+}
+
+// GetSnapshotProgress reports the percent complete of an in-flight
+// cloudsnap transfer by polling Portworx's cloudsnap status API, which
+// tracks bytes already uploaded against the volume's total allocated size.
+func (d *Driver) GetSnapshotProgress(snapID string) (float64, int64, int64, error) {
+	status, err := d.volDriver.CloudBackupStatus(&api.CloudBackupStatusRequest{
+		ID: snapID,
+	})
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	s, ok := status.Statuses[snapID]
+	if !ok {
+		return 0, 0, 0, nil
+	}
+
+	total := s.BytesTotal
+	if total == 0 {
+		return 0, s.BytesDone, total, nil
+	}
+
+	percent := float64(s.BytesDone) / float64(total) * 100
+	return percent, s.BytesDone, total, nil
+}
@@ -0,0 +1,52 @@
+// Package volume defines the interface Stork uses to talk to the
+// underlying storage driver (Portworx, or any other driver that
+// implements it) for the operations the scheduler extender, snapshot
+// controller and migration controller need that aren't already covered by
+// the Kubernetes API: volume placement, snapshot lifecycle and progress.
+package volume
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotSupported is returned by StorkVolumeDriver methods the underlying
+// driver doesn't implement, such as GetSnapshotProgress on a driver with no
+// notion of asynchronous snapshot transfer.
+var ErrNotSupported = errors.New("operation not supported by this volume driver")
+
+// Info describes a single storage volume as seen by the driver, with just
+// enough detail for Stork to reason about placement and snapshot lineage.
+type Info struct {
+	// VolumeID is the driver-internal volume identifier.
+	VolumeID string
+	// VolumeName is the name the driver exposes for the volume.
+	VolumeName string
+	// ParentID is the VolumeID this volume was snapshotted or cloned from,
+	// empty for volumes with no parent.
+	ParentID string
+	// DataNodes lists the nodes that have a replica of this volume's data.
+	DataNodes []string
+}
+
+// StorkVolumeDriver is implemented by each storage backend Stork supports.
+type StorkVolumeDriver interface {
+	// InspectVolume returns driver-level details about the volume
+	// identified by volumeID.
+	InspectVolume(volumeID string) (*Info, error)
+
+	// GetSnapshotProgress returns how far along a snapshot identified by
+	// snapID is, as a percent in [0, 100], along with the bytes
+	// transferred so far and the total bytes expected. Drivers that
+	// cannot report progress (e.g. purely local, synchronous snapshots)
+	// return ErrNotSupported.
+	GetSnapshotProgress(snapID string) (percent float64, bytesTransferred int64, totalBytes int64, err error)
+}
+
+// Progress is a point-in-time snapshot transfer progress reading. The
+// snapshot controller records it as annotations on the snapshot object;
+// it is not written to the object's status.
+type Progress struct {
+	Percent        float64
+	LastUpdateTime time.Time
+}
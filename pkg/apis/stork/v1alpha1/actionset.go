@@ -0,0 +1,138 @@
+package v1alpha1
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ActionSetPhase mirrors the lifecycle of an ActionSet as a whole.
+type ActionSetPhase string
+
+const (
+	// ActionSetPhasePending means no phase has started running yet.
+	ActionSetPhasePending ActionSetPhase = "Pending"
+	// ActionSetPhaseRunning means at least one phase has started and none
+	// have failed yet.
+	ActionSetPhaseRunning ActionSetPhase = "Running"
+	// ActionSetPhaseComplete means every phase finished successfully.
+	ActionSetPhaseComplete ActionSetPhase = "Complete"
+	// ActionSetPhaseFailed means a step failed; the controller does not
+	// start any subsequent phase once this is set.
+	ActionSetPhaseFailed ActionSetPhase = "Failed"
+)
+
+// ActionSet instantiates a Blueprint against a concrete target (a PVC,
+// workload, or group), run by blueprint.Engine. It is the record of what
+// actually ran and how each step fared, the Blueprint/ActionSet equivalent
+// of a Tekton TaskRun for a Task. The snapshot controller in pkg/snapshot
+// does not run ActionSets around its snapshot calls.
+type ActionSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ActionSetSpec   `json:"spec"`
+	Status ActionSetStatus `json:"status,omitempty"`
+}
+
+// ActionSetSpec names the Blueprint to run and the target to run it against.
+type ActionSetSpec struct {
+	// BlueprintName is the Blueprint this ActionSet instantiates.
+	BlueprintName string `json:"blueprintName"`
+	// Target is the object the Blueprint's steps are templated against,
+	// e.g. a PVC or a VolumeGroupSnapshot.
+	Target v1.ObjectReference `json:"target"`
+}
+
+// ActionSetStatus records per-phase, per-step outcomes as the controller
+// works through the Blueprint.
+type ActionSetStatus struct {
+	Phase       ActionSetPhase                  `json:"phase,omitempty"`
+	PhaseStatus map[BlueprintPhase]PhaseStatus  `json:"phaseStatus,omitempty"`
+	// Error is set when Phase is ActionSetPhaseFailed, describing which
+	// step failed and why.
+	Error string `json:"error,omitempty"`
+}
+
+// PhaseStatus records the outcome of every step run for one BlueprintPhase.
+type PhaseStatus struct {
+	StepStatus []StepStatus `json:"stepStatus,omitempty"`
+}
+
+// StepStatus is the recorded outcome of a single BlueprintStep.
+type StepStatus struct {
+	Name      string `json:"name"`
+	Succeeded bool   `json:"succeeded"`
+	Log       string `json:"log,omitempty"`
+}
+
+// ActionSetList is a list of ActionSet resources.
+type ActionSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ActionSet `json:"items"`
+}
+
+// DeepCopyInto copies the receiver into out.
+func (in *ActionSet) DeepCopyInto(out *ActionSet) {
+	*out = *in
+	out.ObjectMeta = *in.ObjectMeta.DeepCopy()
+	if in.Status.PhaseStatus != nil {
+		out.Status.PhaseStatus = make(map[BlueprintPhase]PhaseStatus, len(in.Status.PhaseStatus))
+		for phase, ps := range in.Status.PhaseStatus {
+			steps := make([]StepStatus, len(ps.StepStatus))
+			copy(steps, ps.StepStatus)
+			out.Status.PhaseStatus[phase] = PhaseStatus{StepStatus: steps}
+		}
+	}
+}
+
+// DeepCopy creates a new ActionSet, deep-copying the receiver.
+func (in *ActionSet) DeepCopy() *ActionSet {
+	if in == nil {
+		return nil
+	}
+	out := new(ActionSet)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject implements runtime.Object.
+func (in *ActionSet) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto copies the receiver into out.
+func (in *ActionSetList) DeepCopyInto(out *ActionSetList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		out.Items = make([]ActionSet, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+// DeepCopy creates a new ActionSetList, deep-copying the receiver.
+func (in *ActionSetList) DeepCopy() *ActionSetList {
+	if in == nil {
+		return nil
+	}
+	out := new(ActionSetList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject implements runtime.Object.
+func (in *ActionSetList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package v1alpha1
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// BackupLocationProvider identifies the cloud object-store API a
+// BackupLocation talks to.
+type BackupLocationProvider string
+
+const (
+	// BackupLocationAWS stores snapshots in an S3 (or S3-compatible)
+	// bucket.
+	BackupLocationAWS BackupLocationProvider = "aws"
+	// BackupLocationGCP stores snapshots in a Google Cloud Storage bucket.
+	BackupLocationGCP BackupLocationProvider = "gcp"
+	// BackupLocationAzure stores snapshots in an Azure Blob Storage
+	// container.
+	BackupLocationAzure BackupLocationProvider = "azure"
+	// BackupLocationS3Compatible stores snapshots in a non-AWS,
+	// S3-API-compatible bucket (e.g. MinIO, Ceph RGW), requiring an
+	// explicit endpoint.
+	BackupLocationS3Compatible BackupLocationProvider = "s3-compatible"
+)
+
+// BackupLocation is analogous to Velero's BackupStorageLocation: it names a
+// cloud bucket/container a cloud snapshot can be written to, decoupling
+// "where does this backup live" from the storage driver's own default
+// cloud target. A legacy VolumeSnapshot references one by name via the
+// "stork.libopenstorage.org/backup-location" annotation, and
+// snapshot.Controller.CreateSnapshot takes one by name; VolumeGroupSnapshot
+// objects are not resolved against a BackupLocation.
+type BackupLocation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Location BackupLocationItem `json:"location"`
+}
+
+// BackupLocationItem is the configurable part of a BackupLocation.
+type BackupLocationItem struct {
+	// Provider is the cloud object-store API this location talks to.
+	Provider BackupLocationProvider `json:"provider"`
+	// Bucket is the bucket or container name.
+	Bucket string `json:"bucket"`
+	// Prefix is an optional key prefix under which objects are written,
+	// letting multiple BackupLocations share one bucket.
+	Prefix string `json:"prefix,omitempty"`
+	// Region is the provider region the bucket lives in, if applicable.
+	Region string `json:"region,omitempty"`
+	// Endpoint is required for BackupLocationS3Compatible and is the
+	// S3-API endpoint to talk to instead of AWS's default.
+	Endpoint string `json:"endpoint,omitempty"`
+	// CredentialSecretRef names a Secret in the same namespace holding the
+	// provider-specific credentials (e.g. access/secret key, service
+	// account JSON).
+	CredentialSecretRef *v1.LocalObjectReference `json:"credentialSecretRef,omitempty"`
+	// EncryptionKeySecretRef optionally names a Secret holding the
+	// encryption key the driver should use for objects written here.
+	EncryptionKeySecretRef *v1.LocalObjectReference `json:"encryptionKeySecretRef,omitempty"`
+}
+
+// BackupLocationList is a list of BackupLocation resources.
+type BackupLocationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []BackupLocation `json:"items"`
+}
+
+// DeepCopyInto copies the receiver into out.
+func (in *BackupLocation) DeepCopyInto(out *BackupLocation) {
+	*out = *in
+	out.ObjectMeta = *in.ObjectMeta.DeepCopy()
+	if in.Location.CredentialSecretRef != nil {
+		ref := *in.Location.CredentialSecretRef
+		out.Location.CredentialSecretRef = &ref
+	}
+	if in.Location.EncryptionKeySecretRef != nil {
+		ref := *in.Location.EncryptionKeySecretRef
+		out.Location.EncryptionKeySecretRef = &ref
+	}
+}
+
+// DeepCopy creates a new BackupLocation, deep-copying the receiver.
+func (in *BackupLocation) DeepCopy() *BackupLocation {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupLocation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject implements runtime.Object.
+func (in *BackupLocation) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto copies the receiver into out.
+func (in *BackupLocationList) DeepCopyInto(out *BackupLocationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		out.Items = make([]BackupLocation, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+// DeepCopy creates a new BackupLocationList, deep-copying the receiver.
+func (in *BackupLocationList) DeepCopy() *BackupLocationList {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupLocationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject implements runtime.Object.
+func (in *BackupLocationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
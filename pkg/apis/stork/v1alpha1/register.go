@@ -0,0 +1,47 @@
+// Package v1alpha1 contains the Stork-owned custom resource types and the
+// scheme registration plumbing needed to use them with a generated
+// clientset and the controller-runtime/client-go informer machinery.
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// GroupName is the API group all Stork custom resources live under.
+	GroupName = "stork.libopenstorage.org"
+	// Version is the API version for the types in this package.
+	Version = "v1alpha1"
+)
+
+// SchemeGroupVersion is the group version used to register Stork's types.
+var SchemeGroupVersion = schema.GroupVersion{Group: GroupName, Version: Version}
+
+// SchemeBuilder collects the AddToScheme funcs for every type in this
+// package, for use when building a runtime.Scheme.
+var (
+	SchemeBuilder = runtime.NewSchemeBuilder(addKnownTypes)
+	// AddToScheme applies all the stored functions to the scheme.
+	AddToScheme = SchemeBuilder.AddToScheme
+)
+
+// Resource takes an unqualified resource name and returns it qualified
+// with this package's GroupVersion.
+func Resource(resource string) schema.GroupResource {
+	return SchemeGroupVersion.WithResource(resource).GroupResource()
+}
+
+func addKnownTypes(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypes(SchemeGroupVersion,
+		&StorkSnapshotPolicy{},
+		&StorkSnapshotPolicyList{},
+		&BackupLocation{},
+		&BackupLocationList{},
+		&Blueprint{},
+		&BlueprintList{},
+		&ActionSet{},
+		&ActionSetList{},
+	)
+	return nil
+}
@@ -0,0 +1,109 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// StorkSnapshotPolicy configures per-PVC limits, with per-StorageClass and
+// per-namespace overrides, on how many VolumeSnapshots a PVC may have at
+// once. snapshot.Controller.CreateSnapshot enforces it before creating a
+// CSI VolumeSnapshot, so a cluster operator can cap snapshot churn without
+// each app owner having to coordinate manually. Legacy VolumeSnapshots and
+// VolumeGroupSnapshot members are not checked against it.
+type StorkSnapshotPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec StorkSnapshotPolicySpec `json:"spec"`
+}
+
+// StorkSnapshotPolicySpec is the configurable part of a StorkSnapshotPolicy.
+type StorkSnapshotPolicySpec struct {
+	// MaxSnapshotsPerVolume is the default cap on the number of snapshots
+	// (of any combination of types) a single PVC may have at once. A value
+	// of 0 means unlimited.
+	MaxSnapshotsPerVolume int `json:"maxSnapshotsPerVolume"`
+	// MaxSnapshotsPerStorageClass overrides MaxSnapshotsPerVolume for PVCs
+	// provisioned from a named StorageClass.
+	MaxSnapshotsPerStorageClass map[string]int `json:"maxSnapshotsPerStorageClass,omitempty"`
+	// MaxSnapshotsPerNamespace overrides MaxSnapshotsPerVolume for PVCs in
+	// a named namespace. Namespace overrides take precedence over
+	// StorageClass overrides when both match.
+	MaxSnapshotsPerNamespace map[string]int `json:"maxSnapshotsPerNamespace,omitempty"`
+}
+
+// StorkSnapshotPolicyList is a list of StorkSnapshotPolicy resources.
+type StorkSnapshotPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []StorkSnapshotPolicy `json:"items"`
+}
+
+// DeepCopyInto copies the receiver into out.
+func (in *StorkSnapshotPolicy) DeepCopyInto(out *StorkSnapshotPolicy) {
+	*out = *in
+	out.ObjectMeta = *in.ObjectMeta.DeepCopy()
+	if in.Spec.MaxSnapshotsPerStorageClass != nil {
+		out.Spec.MaxSnapshotsPerStorageClass = make(map[string]int, len(in.Spec.MaxSnapshotsPerStorageClass))
+		for k, v := range in.Spec.MaxSnapshotsPerStorageClass {
+			out.Spec.MaxSnapshotsPerStorageClass[k] = v
+		}
+	}
+	if in.Spec.MaxSnapshotsPerNamespace != nil {
+		out.Spec.MaxSnapshotsPerNamespace = make(map[string]int, len(in.Spec.MaxSnapshotsPerNamespace))
+		for k, v := range in.Spec.MaxSnapshotsPerNamespace {
+			out.Spec.MaxSnapshotsPerNamespace[k] = v
+		}
+	}
+}
+
+// DeepCopy creates a new StorkSnapshotPolicy, deep-copying the receiver.
+func (in *StorkSnapshotPolicy) DeepCopy() *StorkSnapshotPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(StorkSnapshotPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject implements runtime.Object.
+func (in *StorkSnapshotPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto copies the receiver into out.
+func (in *StorkSnapshotPolicyList) DeepCopyInto(out *StorkSnapshotPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		out.Items = make([]StorkSnapshotPolicy, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+// DeepCopy creates a new StorkSnapshotPolicyList, deep-copying the receiver.
+func (in *StorkSnapshotPolicyList) DeepCopy() *StorkSnapshotPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(StorkSnapshotPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject implements runtime.Object.
+func (in *StorkSnapshotPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
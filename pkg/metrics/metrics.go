@@ -0,0 +1,32 @@
+// Package metrics holds the Prometheus metrics Stork exposes for scraping.
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// SnapshotProgressPercent tracks how far along an in-progress snapshot is,
+// labeled by name, namespace and snapshot type (e.g. "cloud", "local",
+// "csi"), so operators and Velero-style callers can watch long-running
+// cloud backups instead of only waiting for completion.
+var SnapshotProgressPercent = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "stork_snapshot_progress_percent",
+		Help: "Percent complete of an in-progress volume snapshot, 0-100.",
+	},
+	[]string{"name", "namespace", "type"},
+)
+
+// SnapshotPolicyRejectedTotal counts VolumeSnapshot requests that
+// PolicyEnforcer.Admit in pkg/snapshot turned away for exceeding a
+// StorkSnapshotPolicy limit, labeled by namespace and storage class.
+var SnapshotPolicyRejectedTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "stork_snapshot_policy_rejected_total",
+		Help: "Number of snapshot requests rejected for exceeding a StorkSnapshotPolicy limit.",
+	},
+	[]string{"namespace", "storageclass"},
+)
+
+func init() {
+	prometheus.MustRegister(SnapshotProgressPercent)
+	prometheus.MustRegister(SnapshotPolicyRejectedTotal)
+}
@@ -0,0 +1,83 @@
+// Package snapshot implements Stork's pluggable VolumeSnapshot backends.
+//
+// Historically Stork only understood the external-storage CRDs
+// (github.com/kubernetes-incubator/external-storage/snapshot/pkg/apis/crd/v1),
+// which are Portworx specific. This package lets the snapshot controller,
+// the stork-snapshot-sc storage class provisioner and their callers work
+// against either that legacy CRD or the CSI snapshot.storage.k8s.io/v1 API
+// implemented by kubernetes-csi/external-snapshotter, so any CSI driver can
+// be used for snapshot/restore, not just Portworx.
+package snapshot
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// Kind identifies which underlying snapshot API a VolumeSnapshot belongs to.
+type Kind string
+
+const (
+	// KindLegacy is the pre-CSI github.com/kubernetes-incubator/external-storage
+	// VolumeSnapshot/VolumeSnapshotData CRD pair, restored via the
+	// client.SnapshotPVCAnnotation annotation.
+	KindLegacy Kind = "legacy"
+	// KindCSI is the upstream snapshot.storage.k8s.io/v1 VolumeSnapshot API,
+	// restored via a PVC's spec.dataSource.
+	KindCSI Kind = "csi"
+)
+
+// CSIDriverSnapshotClassAnnotation records the CSI driver name a
+// VolumeSnapshotClass was selected for, so backends can pick the right
+// class without the caller needing to know about driver-specific naming.
+const CSIDriverSnapshotClassAnnotation = "stork.libopenstorage.org/csi-driver"
+
+// Backend abstracts the pieces of a snapshot/restore flow that differ
+// between the legacy CRD and the CSI v1 API. The snapshot controller holds
+// one Backend per VolumeSnapshot it is driving; callers that only need to
+// restore (e.g. integration tests) can obtain one with ForKind.
+type Backend interface {
+	// Kind returns the backend's identifying Kind.
+	Kind() Kind
+	// RestorePVCSpec returns the annotations and/or DataSource that need to
+	// be set on a PersistentVolumeClaim to restore from the named snapshot.
+	// Legacy backends return annotations; the CSI backend returns a
+	// DataSource referencing the VolumeSnapshot.
+	RestorePVCSpec(snapName, snapNamespace string) (annotations map[string]string, dataSource *v1.TypedLocalObjectReference)
+	// IsReady reports whether the named snapshot has completed and is safe
+	// to restore from.
+	IsReady(snapName, snapNamespace string) (bool, error)
+}
+
+// ForKind returns the Backend implementation for the given Kind.
+func ForKind(kind Kind) (Backend, error) {
+	switch kind {
+	case KindLegacy:
+		return &legacyBackend{}, nil
+	case KindCSI:
+		return &csiBackend{}, nil
+	default:
+		return nil, fmt.Errorf("unknown snapshot backend kind %q", kind)
+	}
+}
+
+// DetectKind infers the Kind of an existing snapshot object from its
+// annotations. Only snapshots carrying CSIDriverSnapshotClassAnnotation
+// (those made by CreateCSISnapshot) are reported as KindCSI; every other
+// snapshot, including a CSI VolumeSnapshot without that annotation such as
+// a GroupSnapshotController member, is reported as KindLegacy.
+func DetectKind(annotations map[string]string) Kind {
+	if _, ok := annotations[CSIDriverSnapshotClassAnnotation]; ok {
+		return KindCSI
+	}
+	return KindLegacy
+}
+
+// BackendForObject resolves the Backend that produced a snapshot object by
+// inspecting its annotations via DetectKind, for callers (e.g. a generic
+// restore path) that receive a snapshot reference without already knowing
+// which API created it.
+func BackendForObject(annotations map[string]string) (Backend, error) {
+	return ForKind(DetectKind(annotations))
+}
@@ -0,0 +1,101 @@
+package snapshot
+
+import (
+	"fmt"
+
+	stork_v1alpha1 "github.com/libopenstorage/stork/pkg/apis/stork/v1alpha1"
+	"github.com/portworx/sched-ops/k8s"
+	v1 "k8s.io/api/core/v1"
+)
+
+// BackupLocationAnnotation names the BackupLocation a legacy VolumeSnapshot
+// references. LegacyController resolves it once the snapshot is ready and
+// records the location's bucket/prefix on the SnapshotData; the location is
+// not passed to the storage driver when the snapshot is taken.
+const BackupLocationAnnotation = "stork.libopenstorage.org/backup-location"
+
+// ResolvedBackupLocationBucketAnnotation and
+// ResolvedBackupLocationPrefixAnnotation are recorded on the resulting
+// SnapshotData once a snapshot completes, so a restore can find the right
+// bucket/prefix even if the cluster's default BackupLocation later changes.
+const (
+	ResolvedBackupLocationBucketAnnotation = "stork.libopenstorage.org/resolved-bucket"
+	ResolvedBackupLocationPrefixAnnotation = "stork.libopenstorage.org/resolved-prefix"
+)
+
+// ResolvedBackupLocation is a BackupLocation with its credentials read out
+// of the referenced Secret(s).
+type ResolvedBackupLocation struct {
+	Provider   stork_v1alpha1.BackupLocationProvider
+	Bucket     string
+	Prefix     string
+	Region     string
+	Endpoint   string
+	Credential map[string][]byte
+	Encryption map[string][]byte
+}
+
+// ResolveBackupLocation reads the named BackupLocation and its credential
+// (and, if set, encryption key) Secrets, returning everything the driver
+// needs to target that location for a cloud snapshot.
+func ResolveBackupLocation(namespace, name string) (*ResolvedBackupLocation, error) {
+	loc, err := k8s.Instance().GetBackupLocation(name, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get BackupLocation %s/%s: %v", namespace, name, err)
+	}
+
+	resolved := &ResolvedBackupLocation{
+		Provider: loc.Location.Provider,
+		Bucket:   loc.Location.Bucket,
+		Prefix:   loc.Location.Prefix,
+		Region:   loc.Location.Region,
+		Endpoint: loc.Location.Endpoint,
+	}
+
+	if ref := loc.Location.CredentialSecretRef; ref != nil {
+		secret, err := k8s.Instance().GetSecret(ref.Name, namespace)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get credential secret %s/%s for BackupLocation %s: %v",
+				namespace, ref.Name, name, err)
+		}
+		resolved.Credential = secretData(secret)
+	}
+
+	if ref := loc.Location.EncryptionKeySecretRef; ref != nil {
+		secret, err := k8s.Instance().GetSecret(ref.Name, namespace)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get encryption key secret %s/%s for BackupLocation %s: %v",
+				namespace, ref.Name, name, err)
+		}
+		resolved.Encryption = secretData(secret)
+	}
+
+	return resolved, nil
+}
+
+// AnnotateSnapshotDataWithBackupLocation records resolved's bucket and
+// prefix onto the SnapshotData's annotations, so a later restore can find
+// them even if the cluster's default BackupLocation changes afterward.
+func AnnotateSnapshotDataWithBackupLocation(snapshotDataName string, resolved *ResolvedBackupLocation) error {
+	sData, err := k8s.Instance().GetSnapshotData(snapshotDataName)
+	if err != nil {
+		return fmt.Errorf("failed to get SnapshotData %s: %v", snapshotDataName, err)
+	}
+
+	if sData.Annotations == nil {
+		sData.Annotations = make(map[string]string)
+	}
+	sData.Annotations[ResolvedBackupLocationBucketAnnotation] = resolved.Bucket
+	sData.Annotations[ResolvedBackupLocationPrefixAnnotation] = resolved.Prefix
+
+	_, err = k8s.Instance().UpdateSnapshotData(sData)
+	return err
+}
+
+func secretData(secret *v1.Secret) map[string][]byte {
+	data := make(map[string][]byte, len(secret.Data))
+	for k, v := range secret.Data {
+		data[k] = v
+	}
+	return data
+}
@@ -0,0 +1,85 @@
+package snapshot
+
+import (
+	"fmt"
+
+	"github.com/libopenstorage/stork/drivers/volume"
+)
+
+// SnapshotRequest is everything the snapshot controller needs to take one
+// VolumeSnapshot: which PVC to snapshot and which CSI driver to snapshot it
+// with. How many snapshots of that PVC already exist, for quota
+// enforcement, is counted by CreateSnapshot itself rather than taken from
+// the caller.
+type SnapshotRequest struct {
+	Namespace        string
+	Name             string
+	PVCName          string
+	StorageClassName string
+	DriverName       string
+}
+
+// Controller is the snapshot controller's single-volume entry point: it
+// admits the request against the cluster's StorkSnapshotPolicy, resolves an
+// explicit BackupLocation if the caller names one, creates the
+// CSI snapshot, and starts polling the driver for progress until it
+// completes. This is the call path PolicyEnforcer, ProgressReporter and
+// ResolveBackupLocation exist to feed; GroupSnapshotController is the
+// equivalent entry point for VolumeGroupSnapshot requests.
+type Controller struct {
+	Policy *PolicyEnforcer
+	Driver volume.StorkVolumeDriver
+}
+
+// CreateSnapshot admits req against the configured StorkSnapshotPolicy,
+// resolves backupLocationName (if non-empty) to make sure it exists before
+// any driver call is made, creates the CSI snapshot, and starts a
+// ProgressReporter for it. It returns once the snapshot has been created;
+// progress reporting continues in the background until the snapshot is
+// ready or done is closed.
+func (c *Controller) CreateSnapshot(req SnapshotRequest, backupLocationName string, done <-chan struct{}) error {
+	policy := c.Policy
+	if policy == nil {
+		policy = &PolicyEnforcer{PolicyName: DefaultPolicyName}
+	}
+	existingCount, err := CountCSISnapshotsForPVC(req.Namespace, req.PVCName)
+	if err != nil {
+		return fmt.Errorf("failed to count existing snapshots for PVC %s/%s: %v", req.Namespace, req.PVCName, err)
+	}
+	if err := policy.Admit(req.Namespace, req.StorageClassName, req.PVCName, existingCount); err != nil {
+		return err
+	}
+
+	if backupLocationName != "" {
+		if _, err := ResolveBackupLocation(req.Namespace, backupLocationName); err != nil {
+			return fmt.Errorf("failed to resolve BackupLocation for snapshot %s/%s: %v", req.Namespace, req.Name, err)
+		}
+	}
+
+	snap, err := CreateCSISnapshot(req.Namespace, req.Name, req.PVCName, req.DriverName)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot %s/%s: %v", req.Namespace, req.Name, err)
+	}
+
+	if c.Driver != nil {
+		backend, backendErr := ForKind(KindCSI)
+		reporter := &ProgressReporter{
+			Driver: c.Driver,
+			UpdateFunc: func(p volume.Progress) error {
+				return UpdateCSISnapshotProgress(snap.Name, snap.Namespace, p.Percent, p.LastUpdateTime)
+			},
+			IsDone: func() (bool, error) {
+				if backendErr != nil {
+					return false, backendErr
+				}
+				return backend.IsReady(snap.Name, snap.Namespace)
+			},
+		}
+		// Run exits on its own once IsDone reports the snapshot has
+		// finished; done only matters for a caller that wants to abort
+		// reporting early (e.g. on shutdown).
+		go reporter.Run(snap.Name, snap.Name, snap.Namespace, string(KindCSI), done)
+	}
+
+	return nil
+}
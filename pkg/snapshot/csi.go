@@ -0,0 +1,206 @@
+package snapshot
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
+	snapclient "github.com/kubernetes-csi/external-snapshotter/client/v4/clientset/versioned"
+	"k8s.io/client-go/rest"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var csiSnapshotClient snapclient.Interface
+
+// SetCSIClient injects the external-snapshotter clientset used by the CSI
+// backend. It must be called once during controller/driver initialization,
+// mirroring how sched-ops' k8s.Instance() is seeded elsewhere in Stork.
+func SetCSIClient(c snapclient.Interface) {
+	csiSnapshotClient = c
+}
+
+func csiClient() (snapclient.Interface, error) {
+	if csiSnapshotClient != nil {
+		return csiSnapshotClient, nil
+	}
+
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CSI snapshot client config: %v", err)
+	}
+	c, err := snapclient.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	csiSnapshotClient = c
+	return csiSnapshotClient, nil
+}
+
+// csiBackend restores from a snapshot.storage.k8s.io/v1 VolumeSnapshot via
+// a PVC's spec.dataSource, as implemented by any CSI driver's external
+// snapshotter/provisioner sidecars.
+type csiBackend struct{}
+
+func (b *csiBackend) Kind() Kind {
+	return KindCSI
+}
+
+func (b *csiBackend) RestorePVCSpec(snapName, snapNamespace string) (map[string]string, *v1.TypedLocalObjectReference) {
+	apiGroup := snapshotv1.GroupName
+	return nil, &v1.TypedLocalObjectReference{
+		APIGroup: &apiGroup,
+		Kind:     "VolumeSnapshot",
+		Name:     snapName,
+	}
+}
+
+func (b *csiBackend) IsReady(snapName, snapNamespace string) (bool, error) {
+	c, err := csiClient()
+	if err != nil {
+		return false, err
+	}
+
+	snap, err := c.SnapshotV1().VolumeSnapshots(snapNamespace).Get(snapName, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+
+	return snap.Status != nil && snap.Status.ReadyToUse != nil && *snap.Status.ReadyToUse, nil
+}
+
+// GetCSISnapshot fetches the raw VolumeSnapshot object, for callers (e.g.
+// DetectKind-based dispatch) that need its annotations before they know
+// which Backend to use.
+func GetCSISnapshot(name, namespace string) (*snapshotv1.VolumeSnapshot, error) {
+	c, err := csiClient()
+	if err != nil {
+		return nil, err
+	}
+	return c.SnapshotV1().VolumeSnapshots(namespace).Get(name, metav1.GetOptions{})
+}
+
+// DeleteCSISnapshot deletes a VolumeSnapshot created via the CSI backend,
+// e.g. one returned by CreateCSISnapshot.
+func DeleteCSISnapshot(name, namespace string) error {
+	c, err := csiClient()
+	if err != nil {
+		return err
+	}
+	return c.SnapshotV1().VolumeSnapshots(namespace).Delete(name, nil)
+}
+
+// UpdateCSISnapshotProgress records percent and updatedAt as annotations on
+// a CSI VolumeSnapshot, the ProgressReporter.UpdateFunc a Controller wires
+// up for a snapshot created via CreateCSISnapshot.
+func UpdateCSISnapshotProgress(name, namespace string, percent float64, updatedAt time.Time) error {
+	c, err := csiClient()
+	if err != nil {
+		return err
+	}
+
+	snap, err := c.SnapshotV1().VolumeSnapshots(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	if snap.Annotations == nil {
+		snap.Annotations = make(map[string]string)
+	}
+	snap.Annotations[ProgressPercentAnnotation] = strconv.FormatFloat(percent, 'f', -1, 64)
+	snap.Annotations[ProgressUpdatedAtAnnotation] = updatedAt.Format(time.RFC3339)
+
+	_, err = c.SnapshotV1().VolumeSnapshots(namespace).Update(snap)
+	return err
+}
+
+// CountCSISnapshotsForPVC counts the existing CSI VolumeSnapshots in
+// namespace sourced from pvcName, for a Controller to enforce a
+// StorkSnapshotPolicy's per-PVC limit against the actual cluster state
+// rather than trusting a caller-supplied count.
+func CountCSISnapshotsForPVC(namespace, pvcName string) (int, error) {
+	c, err := csiClient()
+	if err != nil {
+		return 0, err
+	}
+
+	snaps, err := c.SnapshotV1().VolumeSnapshots(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list VolumeSnapshots in %s: %v", namespace, err)
+	}
+
+	count := 0
+	for i := range snaps.Items {
+		src := snaps.Items[i].Spec.Source.PersistentVolumeClaimName
+		if src != nil && *src == pvcName {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// selectSnapshotClassForDriver picks the VolumeSnapshotClass to use for a
+// VolumeSnapshot taken against driverName. If more than one class targets
+// the driver, the one explicitly tagged with CSIDriverSnapshotClassAnnotation
+// wins; otherwise the first matching class is used.
+func selectSnapshotClassForDriver(c snapclient.Interface, driverName string) (*snapshotv1.VolumeSnapshotClass, error) {
+	classes, err := c.SnapshotV1().VolumeSnapshotClasses().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VolumeSnapshotClasses: %v", err)
+	}
+
+	var fallback *snapshotv1.VolumeSnapshotClass
+	for i := range classes.Items {
+		class := &classes.Items[i]
+		if class.Driver != driverName {
+			continue
+		}
+		if _, ok := class.Annotations[CSIDriverSnapshotClassAnnotation]; ok {
+			return class, nil
+		}
+		if fallback == nil {
+			fallback = class
+		}
+	}
+	if fallback != nil {
+		return fallback, nil
+	}
+	return nil, fmt.Errorf("no VolumeSnapshotClass found for CSI driver %q", driverName)
+}
+
+// CreateCSISnapshot creates a VolumeSnapshot for pvcName, selecting its
+// VolumeSnapshotClass by driverName rather than requiring the caller to
+// already know the class name, and tags the result with
+// CSIDriverSnapshotClassAnnotation so a later caller holding only the
+// object (not the driver name) can still recover its Kind via DetectKind.
+func CreateCSISnapshot(namespace, name, pvcName, driverName string) (*snapshotv1.VolumeSnapshot, error) {
+	c, err := csiClient()
+	if err != nil {
+		return nil, err
+	}
+
+	class, err := selectSnapshotClassForDriver(c, driverName)
+	if err != nil {
+		return nil, err
+	}
+
+	snap := &snapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Annotations: map[string]string{
+				CSIDriverSnapshotClassAnnotation: driverName,
+			},
+		},
+		Spec: snapshotv1.VolumeSnapshotSpec{
+			Source: snapshotv1.VolumeSnapshotSource{
+				PersistentVolumeClaimName: &pvcName,
+			},
+			VolumeSnapshotClassName: &class.Name,
+		},
+	}
+
+	return c.SnapshotV1().VolumeSnapshots(namespace).Create(snap)
+}
@@ -0,0 +1,268 @@
+package snapshot
+
+import (
+	"fmt"
+	"time"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
+	groupsnapshotv1alpha1 "github.com/kubernetes-csi/external-snapshotter/client/v7/apis/volumegroupsnapshot/v1alpha1"
+	"github.com/portworx/sched-ops/k8s"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// groupReconcilePollInterval is how often Run lists VolumeGroupSnapshot
+// objects and reconciles each one.
+const groupReconcilePollInterval = 10 * time.Second
+
+// GroupSnapshotController reconciles VolumeGroupSnapshot objects. When the
+// CSI driver named by the VolumeGroupSnapshotClass supports native group
+// snapshots it leaves fan-out to the driver's sidecars and only aggregates
+// status; otherwise it simulates a group by taking one VolumeSnapshot per
+// matching PVC (the application is not quiesced around them), atomically: if
+// any member fails,
+// the members created by this call are deleted so a retry starts from a
+// clean slate rather than leaving an inconsistent partial group around.
+type GroupSnapshotController struct {
+	// NativeGroupDrivers lists CSI driver names known to implement group
+	// snapshots natively via VolumeGroupSnapshotContent.
+	NativeGroupDrivers map[string]bool
+}
+
+// Run polls for VolumeGroupSnapshot objects across all namespaces and
+// reconciles each one until stopCh is closed. This is the controller's
+// actual entry point.
+func (c *GroupSnapshotController) Run(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(groupReconcilePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			c.syncAll()
+		}
+	}
+}
+
+func (c *GroupSnapshotController) syncAll() {
+	groups, err := k8s.Instance().ListVolumeGroupSnapshots("")
+	if err != nil {
+		logrus.Errorf("failed to list group snapshots: %v", err)
+		return
+	}
+
+	for i := range groups.Items {
+		gs := &groups.Items[i]
+		class, err := k8s.Instance().GetVolumeGroupSnapshotClass(gs.Spec.VolumeGroupSnapshotClassName)
+		if err != nil {
+			logrus.Errorf("failed to get VolumeGroupSnapshotClass for group snapshot %s/%s: %v", gs.Namespace, gs.Name, err)
+			continue
+		}
+		if err := c.Reconcile(gs, class); err != nil {
+			logrus.Errorf("failed to reconcile group snapshot %s/%s: %v", gs.Namespace, gs.Name, err)
+		}
+	}
+}
+
+// Reconcile drives gs towards a Ready or Error status. It is safe to call
+// repeatedly on the same gs, as any reconcile loop does on resync/requeue.
+func (c *GroupSnapshotController) Reconcile(
+	gs *groupsnapshotv1alpha1.VolumeGroupSnapshot,
+	class *groupsnapshotv1alpha1.VolumeGroupSnapshotClass,
+) error {
+	if c.NativeGroupDrivers[class.Driver] {
+		return c.reconcileNative(gs)
+	}
+	return c.reconcileSimulated(gs, class)
+}
+
+// reconcileNative mirrors the CSI driver's native VolumeGroupSnapshotContent
+// status onto gs; the sidecar already guarantees atomicity of member
+// creation, so there's nothing for Stork to fan out itself.
+func (c *GroupSnapshotController) reconcileNative(gs *groupsnapshotv1alpha1.VolumeGroupSnapshot) error {
+	if gs.Status == nil || gs.Status.BoundVolumeGroupSnapshotContentName == nil {
+		// Sidecar hasn't bound a VolumeGroupSnapshotContent yet.
+		return nil
+	}
+
+	content, err := k8s.Instance().GetVolumeGroupSnapshotContent(*gs.Status.BoundVolumeGroupSnapshotContentName)
+	if err != nil {
+		return fmt.Errorf("failed to get VolumeGroupSnapshotContent %s for group snapshot %s/%s: %v",
+			*gs.Status.BoundVolumeGroupSnapshotContentName, gs.Namespace, gs.Name, err)
+	}
+
+	if content.Status != nil && content.Status.Error != nil && content.Status.Error.Message != nil {
+		if updErr := k8s.Instance().UpdateVolumeGroupSnapshotStatus(gs, false); updErr != nil {
+			logrus.Errorf("failed to persist error status for group snapshot %s/%s: %v", gs.Namespace, gs.Name, updErr)
+		}
+		return fmt.Errorf("snapshot failed due to err: group snapshot %s/%s: %s",
+			gs.Namespace, gs.Name, *content.Status.Error.Message)
+	}
+
+	ready := content.Status != nil && content.Status.ReadyToUse != nil && *content.Status.ReadyToUse
+	logrus.Debugf("group snapshot %s/%s backed natively by %s, ready=%v",
+		gs.Namespace, gs.Name, *gs.Status.BoundVolumeGroupSnapshotContentName, ready)
+	return k8s.Instance().UpdateVolumeGroupSnapshotStatus(gs, ready)
+}
+
+// reconcileSimulated lists the PVCs matching gs.Spec.Source.Selector and
+// ensures each has a member VolumeSnapshot, creating any that are missing.
+// It is idempotent: a member that already exists and isn't in an error
+// state is left alone (adopted) rather than recreated, so resyncing a
+// group that already succeeded, or resuming one that was partially
+// created, never tears down healthy members still in use. Only members
+// created by *this* call are rolled back if a later member in the same
+// call fails.
+func (c *GroupSnapshotController) reconcileSimulated(
+	gs *groupsnapshotv1alpha1.VolumeGroupSnapshot,
+	class *groupsnapshotv1alpha1.VolumeGroupSnapshotClass,
+) error {
+	selector, err := metav1.LabelSelectorAsSelector(gs.Spec.Source.Selector)
+	if err != nil {
+		return fmt.Errorf("invalid PVC selector for group snapshot %s/%s: %v", gs.Namespace, gs.Name, err)
+	}
+
+	pvcs, err := k8s.Instance().ListPersistentVolumeClaims(gs.Namespace, selector.String())
+	if err != nil {
+		return fmt.Errorf("failed to list PVCs for group snapshot %s/%s: %v", gs.Namespace, gs.Name, err)
+	}
+	if len(pvcs.Items) == 0 {
+		return fmt.Errorf("no PVCs matched selector for group snapshot %s/%s", gs.Namespace, gs.Name)
+	}
+
+	createdThisCall := make([]string, 0, len(pvcs.Items))
+	for _, pvc := range pvcs.Items {
+		memberName := fmt.Sprintf("%s-%s", gs.Name, pvc.Name)
+
+		existing, getErr := c.getMember(gs.Namespace, memberName)
+		if getErr != nil && !apierrors.IsNotFound(getErr) {
+			c.rollback(gs.Namespace, createdThisCall)
+			return fmt.Errorf("failed to check for existing member %s of group %s/%s: %v",
+				pvc.Name, gs.Namespace, gs.Name, getErr)
+		}
+		if existing != nil && !memberFailed(existing) {
+			// Already created by a previous reconcile and healthy (or
+			// still in flight) — adopt it instead of recreating.
+			continue
+		}
+		if existing != nil && memberFailed(existing) {
+			// Left over from a previous failed attempt; clear it before
+			// retrying so Create doesn't return AlreadyExists.
+			if delErr := c.deleteMember(gs.Namespace, memberName); delErr != nil {
+				c.rollback(gs.Namespace, createdThisCall)
+				return fmt.Errorf("failed to clean up failed member %s of group %s/%s before retry: %v",
+					pvc.Name, gs.Namespace, gs.Name, delErr)
+			}
+		}
+
+		member := &snapshotv1.VolumeSnapshot{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      memberName,
+				Namespace: gs.Namespace,
+				Labels: map[string]string{
+					"stork.libopenstorage.org/group-snapshot": gs.Name,
+				},
+				OwnerReferences: []metav1.OwnerReference{ownerRef(gs)},
+			},
+			Spec: snapshotv1.VolumeSnapshotSpec{
+				Source: snapshotv1.VolumeSnapshotSource{
+					PersistentVolumeClaimName: &pvc.Name,
+				},
+				VolumeSnapshotClassName: &class.Name,
+			},
+		}
+
+		if err := c.createMember(member); err != nil {
+			c.rollback(gs.Namespace, createdThisCall)
+			if updErr := k8s.Instance().UpdateVolumeGroupSnapshotStatus(gs, false); updErr != nil {
+				logrus.Errorf("failed to persist error status for group snapshot %s/%s: %v", gs.Namespace, gs.Name, updErr)
+			}
+			return fmt.Errorf("failed to snapshot member %s of group %s/%s, rolled back members created by this reconcile: %v",
+				pvc.Name, gs.Namespace, gs.Name, err)
+		}
+		createdThisCall = append(createdThisCall, memberName)
+	}
+
+	allReady := true
+	for _, pvc := range pvcs.Items {
+		memberName := fmt.Sprintf("%s-%s", gs.Name, pvc.Name)
+		member, err := c.getMember(gs.Namespace, memberName)
+		if err != nil {
+			return fmt.Errorf("failed to check status of member %s of group snapshot %s/%s: %v",
+				pvc.Name, gs.Namespace, gs.Name, err)
+		}
+		if memberFailed(member) {
+			if updErr := k8s.Instance().UpdateVolumeGroupSnapshotStatus(gs, false); updErr != nil {
+				logrus.Errorf("failed to persist error status for group snapshot %s/%s: %v", gs.Namespace, gs.Name, updErr)
+			}
+			return fmt.Errorf("snapshot failed due to err: member %s of group snapshot %s/%s failed",
+				pvc.Name, gs.Namespace, gs.Name)
+		}
+		if member.Status == nil || member.Status.ReadyToUse == nil || !*member.Status.ReadyToUse {
+			allReady = false
+		}
+	}
+	if !allReady {
+		// Members are still being provisioned; leave gs's status alone and
+		// let the next reconcile check again.
+		return nil
+	}
+
+	return k8s.Instance().UpdateVolumeGroupSnapshotStatus(gs, true)
+}
+
+// memberFailed reports whether an existing member snapshot is in an error
+// state and should be recreated rather than adopted.
+func memberFailed(member *snapshotv1.VolumeSnapshot) bool {
+	return member.Status != nil && member.Status.Error != nil
+}
+
+func (c *GroupSnapshotController) getMember(namespace, name string) (*snapshotv1.VolumeSnapshot, error) {
+	client, err := csiClient()
+	if err != nil {
+		return nil, err
+	}
+	return client.SnapshotV1().VolumeSnapshots(namespace).Get(name, metav1.GetOptions{})
+}
+
+func (c *GroupSnapshotController) createMember(snap *snapshotv1.VolumeSnapshot) error {
+	client, err := csiClient()
+	if err != nil {
+		return err
+	}
+	_, err = client.SnapshotV1().VolumeSnapshots(snap.Namespace).Create(snap)
+	return err
+}
+
+func (c *GroupSnapshotController) deleteMember(namespace, name string) error {
+	client, err := csiClient()
+	if err != nil {
+		return err
+	}
+	return client.SnapshotV1().VolumeSnapshots(namespace).Delete(name, nil)
+}
+
+// rollback deletes every member snapshot created by the reconcile call
+// that failed, keeping a simulated group all-or-nothing without touching
+// members that were adopted from a previous, already-healthy reconcile.
+func (c *GroupSnapshotController) rollback(namespace string, memberNames []string) {
+	for _, name := range memberNames {
+		if err := c.deleteMember(namespace, name); err != nil {
+			logrus.Errorf("failed to roll back group snapshot member %s/%s: %v", namespace, name, err)
+		}
+	}
+}
+
+func ownerRef(gs *groupsnapshotv1alpha1.VolumeGroupSnapshot) metav1.OwnerReference {
+	blockOwnerDeletion := true
+	return metav1.OwnerReference{
+		APIVersion:         groupsnapshotv1alpha1.SchemeGroupVersion.String(),
+		Kind:               "VolumeGroupSnapshot",
+		Name:               gs.Name,
+		UID:                gs.UID,
+		BlockOwnerDeletion: &blockOwnerDeletion,
+	}
+}
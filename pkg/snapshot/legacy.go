@@ -0,0 +1,59 @@
+package snapshot
+
+import (
+	"strconv"
+	"time"
+
+	crdv1 "github.com/kubernetes-incubator/external-storage/snapshot/pkg/apis/crd/v1"
+	client "github.com/kubernetes-incubator/external-storage/snapshot/pkg/client"
+	"github.com/portworx/sched-ops/k8s"
+	v1 "k8s.io/api/core/v1"
+)
+
+// legacyBackend restores from the external-storage VolumeSnapshot CRD via
+// the well-known SnapshotPVCAnnotation annotation.
+type legacyBackend struct{}
+
+func (b *legacyBackend) Kind() Kind {
+	return KindLegacy
+}
+
+func (b *legacyBackend) RestorePVCSpec(snapName, snapNamespace string) (map[string]string, *v1.TypedLocalObjectReference) {
+	return map[string]string{
+		client.SnapshotPVCAnnotation: snapName,
+	}, nil
+}
+
+func (b *legacyBackend) IsReady(snapName, snapNamespace string) (bool, error) {
+	snap, err := k8s.Instance().GetSnapshot(snapName, snapNamespace)
+	if err != nil {
+		return false, err
+	}
+
+	for _, cond := range snap.Status.Conditions {
+		if cond.Type == crdv1.VolumeSnapshotConditionReady {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// UpdateLegacySnapshotProgress records percent and updatedAt as annotations
+// on a legacy VolumeSnapshot, mirroring UpdateCSISnapshotProgress for
+// callers (LegacyController) driving the external-storage cloudsnap flow
+// instead of the CSI one.
+func UpdateLegacySnapshotProgress(name, namespace string, percent float64, updatedAt time.Time) error {
+	snap, err := k8s.Instance().GetSnapshot(name, namespace)
+	if err != nil {
+		return err
+	}
+
+	if snap.Annotations == nil {
+		snap.Annotations = make(map[string]string)
+	}
+	snap.Annotations[ProgressPercentAnnotation] = strconv.FormatFloat(percent, 'f', -1, 64)
+	snap.Annotations[ProgressUpdatedAtAnnotation] = updatedAt.Format(time.RFC3339)
+
+	_, err = k8s.Instance().UpdateSnapshot(snap)
+	return err
+}
@@ -0,0 +1,113 @@
+package snapshot
+
+import (
+	"fmt"
+	"time"
+
+	crdv1 "github.com/kubernetes-incubator/external-storage/snapshot/pkg/apis/crd/v1"
+	"github.com/libopenstorage/stork/drivers/volume"
+	"github.com/portworx/sched-ops/k8s"
+	"github.com/sirupsen/logrus"
+)
+
+// legacyReconcilePollInterval is how often LegacyController lists legacy
+// VolumeSnapshot objects and reconciles each one.
+const legacyReconcilePollInterval = 10 * time.Second
+
+// LegacyController drives the parts of the legacy (external-storage CRD)
+// cloudsnap flow that aren't already handled by the external-storage
+// snapshot controller and the storage driver: reporting transfer progress
+// while a cloudsnap is in flight, and once it's ready, resolving an
+// explicit BackupLocationAnnotation and recording that location's
+// bucket/prefix onto the SnapshotData, so a later restore can find it. The
+// location is not passed to the storage driver.
+// Controller is the equivalent entry point for the CSI backend.
+type LegacyController struct {
+	Driver volume.StorkVolumeDriver
+}
+
+// Run polls for legacy VolumeSnapshot objects across all namespaces and
+// reconciles each one until stopCh is closed.
+func (c *LegacyController) Run(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(legacyReconcilePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			c.syncAll()
+		}
+	}
+}
+
+func (c *LegacyController) syncAll() {
+	snaps, err := k8s.Instance().ListSnapshots("")
+	if err != nil {
+		logrus.Errorf("failed to list legacy snapshots: %v", err)
+		return
+	}
+
+	for i := range snaps.Items {
+		snap := &snaps.Items[i]
+		if err := c.Reconcile(snap); err != nil {
+			logrus.Errorf("failed to reconcile legacy snapshot %s/%s: %v", snap.Namespace, snap.Name, err)
+		}
+	}
+}
+
+// Reconcile reports the latest transfer progress for snap and, once it's
+// ready, resolves its BackupLocationAnnotation (if any) and stamps the
+// resulting SnapshotData with that location's bucket/prefix. It is
+// safe to call repeatedly on the same snap.
+func (c *LegacyController) Reconcile(snap *crdv1.VolumeSnapshot) error {
+	backend := &legacyBackend{}
+	ready, err := backend.IsReady(snap.Name, snap.Namespace)
+	if err != nil {
+		return fmt.Errorf("failed to check readiness of legacy snapshot %s/%s: %v", snap.Namespace, snap.Name, err)
+	}
+
+	if !ready {
+		// Only poll for progress while the cloudsnap is still in flight;
+		// once it's ready there's nothing left to report.
+		if c.Driver != nil {
+			percent, _, _, err := c.Driver.GetSnapshotProgress(snap.Name)
+			switch {
+			case err == volume.ErrNotSupported:
+				// Driver can't report progress; nothing to record.
+			case err != nil:
+				logrus.Errorf("failed to get snapshot progress for %s/%s: %v", snap.Namespace, snap.Name, err)
+			default:
+				if updErr := UpdateLegacySnapshotProgress(snap.Name, snap.Namespace, percent, time.Now()); updErr != nil {
+					logrus.Errorf("failed to persist snapshot progress for %s/%s: %v", snap.Namespace, snap.Name, updErr)
+				}
+			}
+		}
+		return nil
+	}
+
+	locName, ok := snap.Annotations[BackupLocationAnnotation]
+	if !ok || snap.Spec.SnapshotDataName == "" {
+		return nil
+	}
+
+	sData, err := k8s.Instance().GetSnapshotData(snap.Spec.SnapshotDataName)
+	if err != nil {
+		return fmt.Errorf("failed to get SnapshotData %s for snapshot %s/%s: %v",
+			snap.Spec.SnapshotDataName, snap.Namespace, snap.Name, err)
+	}
+	if _, alreadyStamped := sData.Annotations[ResolvedBackupLocationBucketAnnotation]; alreadyStamped {
+		// Already resolved and stamped by a previous reconcile; nothing
+		// left to do, and BackupLocation's credentials shouldn't be
+		// re-read on every poll once that's settled.
+		return nil
+	}
+
+	resolved, err := ResolveBackupLocation(snap.Namespace, locName)
+	if err != nil {
+		return fmt.Errorf("failed to resolve BackupLocation %s for snapshot %s/%s: %v", locName, snap.Namespace, snap.Name, err)
+	}
+
+	return AnnotateSnapshotDataWithBackupLocation(snap.Spec.SnapshotDataName, resolved)
+}
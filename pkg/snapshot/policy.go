@@ -0,0 +1,63 @@
+package snapshot
+
+import (
+	"fmt"
+
+	"github.com/libopenstorage/stork/pkg/metrics"
+	"github.com/portworx/sched-ops/k8s"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// DefaultPolicyName is the name Controller.CreateSnapshot enforces against
+// when the Controller has no PolicyEnforcer, matching the convention that
+// Stork's singleton config CRs are named "default".
+const DefaultPolicyName = "default"
+
+// PolicyEnforcer checks a proposed VolumeSnapshot against the cluster's
+// StorkSnapshotPolicy. Controller.CreateSnapshot calls it before creating a
+// CSI snapshot; LegacyController and GroupSnapshotController do not call it.
+type PolicyEnforcer struct {
+	// PolicyName is the StorkSnapshotPolicy to enforce. Stork supports a
+	// single cluster-wide policy object, same as its other singleton
+	// config CRs.
+	PolicyName string
+}
+
+// Admit returns nil if another snapshot of pvcName/storageClass in
+// namespace is allowed, or an error describing which limit would be
+// exceeded otherwise. existingCount is the number of snapshots the caller
+// has already counted for that PVC.
+func (p *PolicyEnforcer) Admit(namespace, storageClass, pvcName string, existingCount int) error {
+	policy, err := k8s.Instance().GetStorkSnapshotPolicy(p.PolicyName)
+	if apierrors.IsNotFound(err) {
+		// No policy configured means no limit is enforced.
+		return nil
+	}
+	if err != nil {
+		// Fail closed: an RBAC failure or API-server timeout here must not
+		// be treated the same as "no policy configured", or a transient
+		// outage would silently disable the quota feature entirely.
+		return fmt.Errorf("failed to get StorkSnapshotPolicy %s: %v", p.PolicyName, err)
+	}
+
+	limit := policy.Spec.MaxSnapshotsPerVolume
+	if nsLimit, ok := policy.Spec.MaxSnapshotsPerNamespace[namespace]; ok {
+		limit = nsLimit
+	} else if scLimit, ok := policy.Spec.MaxSnapshotsPerStorageClass[storageClass]; ok {
+		limit = scLimit
+	}
+
+	if limit <= 0 {
+		// 0 (or unset) means unlimited.
+		return nil
+	}
+
+	if existingCount >= limit {
+		metrics.SnapshotPolicyRejectedTotal.WithLabelValues(namespace, storageClass).Inc()
+		return fmt.Errorf("snapshot failed due to err: PVC %s/%s already has %d snapshot(s), "+
+			"which meets or exceeds the %d allowed by StorkSnapshotPolicy %s",
+			namespace, pvcName, existingCount, limit, p.PolicyName)
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package snapshot
+
+import (
+	"time"
+
+	"github.com/libopenstorage/stork/drivers/volume"
+	"github.com/libopenstorage/stork/pkg/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// progressPollInterval is how often the controller re-polls the driver for
+// an in-flight snapshot's transfer progress.
+const progressPollInterval = 10 * time.Second
+
+// ProgressPercentAnnotation and ProgressUpdatedAtAnnotation are where an
+// UpdateFunc persists the latest Progress onto a snapshot object, so it can
+// be read back without needing Prometheus.
+const (
+	ProgressPercentAnnotation   = "stork.libopenstorage.org/snapshot-progress-percent"
+	ProgressUpdatedAtAnnotation = "stork.libopenstorage.org/snapshot-progress-updated-at"
+)
+
+// ProgressReporter periodically polls a StorkVolumeDriver for the progress
+// of a cloud snapshot and records it both on the snapshot object (via
+// UpdateFunc) and as a Prometheus gauge, until the snapshot completes.
+type ProgressReporter struct {
+	Driver volume.StorkVolumeDriver
+	// UpdateFunc persists the latest Progress onto the snapshot object; it
+	// is injected so this package doesn't need to know whether the caller
+	// is driving a legacy or CSI snapshot.
+	UpdateFunc func(volume.Progress) error
+	// IsDone reports whether the snapshot has finished (successfully or
+	// not), so Run can stop polling once there's nothing left to report.
+	// Callers that already have another way to signal completion (e.g. a
+	// done channel they close themselves) may leave this nil.
+	IsDone func() (bool, error)
+}
+
+// Run polls until the snapshot is done (per IsDone), done is closed, or the
+// driver returns volume.ErrNotSupported, in which case it exits without
+// error: not every driver can report progress, and that's not a failure.
+func (r *ProgressReporter) Run(snapID, name, namespace, snapType string, done <-chan struct{}) error {
+	ticker := time.NewTicker(progressPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return nil
+		case <-ticker.C:
+			if r.IsDone != nil {
+				isDone, err := r.IsDone()
+				if err != nil {
+					logrus.Errorf("failed to check completion of snapshot %s/%s: %v", namespace, name, err)
+				} else if isDone {
+					return nil
+				}
+			}
+
+			percent, _, _, err := r.Driver.GetSnapshotProgress(snapID)
+			if err == volume.ErrNotSupported {
+				return nil
+			}
+			if err != nil {
+				logrus.Errorf("failed to get snapshot progress for %s/%s: %v", namespace, name, err)
+				continue
+			}
+
+			metrics.SnapshotProgressPercent.WithLabelValues(name, namespace, snapType).Set(percent)
+
+			if r.UpdateFunc != nil {
+				if err := r.UpdateFunc(volume.Progress{Percent: percent, LastUpdateTime: time.Now()}); err != nil {
+					logrus.Errorf("failed to persist snapshot progress for %s/%s: %v", namespace, name, err)
+				}
+			}
+		}
+	}
+}